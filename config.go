@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"time"
+)
+
+// Config holds the runtime configuration for gnw, assembled from command
+// line flags.
+type Config struct {
+	Hostname    string
+	Hood        string
+	Hoodid      string
+	Contact     string
+	Distname    string
+	Distversion string
+	Lat         float64
+	Lng         float64
+	Debug       bool
+	Dry         bool
+
+	// Daemon, when set, makes gnw run as a long-lived process instead of
+	// performing a single crawl-and-post and exiting.
+	Daemon bool
+	// CrawlInterval is how often the daemon performs a crawl-and-post
+	// cycle.
+	CrawlInterval time.Duration
+
+	// MetricsAddr, when set, makes gnw serve a Prometheus "/metrics"
+	// endpoint on this address in addition to posting to alfred.
+	MetricsAddr string
+
+	// Routing-daemon neighbour discovery. Each source can be enabled
+	// independently since a mesh may run more than one at a time.
+	BabelEnabled  bool
+	BabelEndpoint string
+
+	OLSR2Enabled  bool
+	OLSR2Endpoint string
+
+	Bmx7Enabled  bool
+	Bmx7Endpoint string
+}
+
+func getConfig() (Config, error) {
+	var c Config
+
+	flag.StringVar(&c.Hostname, "hostname", "", "node hostname reported to alfred")
+	flag.StringVar(&c.Hood, "hood", "", "neighbourhood name")
+	flag.StringVar(&c.Hoodid, "hoodid", "", "neighbourhood id")
+	flag.StringVar(&c.Contact, "contact", "", "contact information for the node operator")
+	flag.StringVar(&c.Distname, "distname", "", "distribution name")
+	flag.StringVar(&c.Distversion, "distversion", "", "distribution version")
+	flag.Float64Var(&c.Lat, "lat", 0, "node latitude")
+	flag.Float64Var(&c.Lng, "lng", 0, "node longitude")
+	flag.BoolVar(&c.Debug, "debug", false, "print debug output")
+	flag.BoolVar(&c.Dry, "dry", false, "skip posting to alfred")
+	flag.BoolVar(&c.Daemon, "daemon", false, "run as a long-lived daemon instead of exiting after one crawl")
+	flag.DurationVar(&c.CrawlInterval, "crawl-interval", 60*time.Second, "interval between crawls in daemon mode")
+	flag.StringVar(&c.MetricsAddr, "metrics-addr", "", "bind address for the Prometheus /metrics endpoint, e.g. :9100 (disabled if empty)")
+
+	flag.BoolVar(&c.BabelEnabled, "babel-enabled", true, "discover neighbours via babeld")
+	flag.StringVar(&c.BabelEndpoint, "babel-endpoint", "[::1]:33123", "babeld control socket address")
+	flag.BoolVar(&c.OLSR2Enabled, "olsr2-enabled", false, "discover neighbours via OLSR2 jsoninfo")
+	flag.StringVar(&c.OLSR2Endpoint, "olsr2-endpoint", "[::1]:2009", "OLSR2 jsoninfo plugin address")
+	flag.BoolVar(&c.Bmx7Enabled, "bmx7-enabled", false, "discover neighbours via bmx7's status JSON query")
+	flag.StringVar(&c.Bmx7Endpoint, "bmx7-endpoint", "[::1]:6270", "bmx7 status socket address")
+
+	flag.Parse()
+
+	return c, nil
+}
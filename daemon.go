@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	backoffBaseDelay  = 1 * time.Second
+	backoffMaxDelay   = 30 * time.Second
+	backoffMaxRetries = 5
+)
+
+// alfredPostSuccessTotal, alfredPostFailureTotal and
+// alfredLastSuccessTimestamp track the health of the crawl-and-post cycle
+// for the lifetime of the process. They're package-level, rather than
+// threaded through call sites, so the /metrics endpoint can register and
+// report on them regardless of how runCycle is reached.
+var (
+	alfredPostSuccessTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "gnw_alfred_post_success_total",
+		Help: "Successful crawl-and-post cycles.",
+	})
+	alfredPostFailureTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "gnw_alfred_post_failure_total",
+		Help: "Failed crawl-and-post cycles.",
+	})
+	alfredLastSuccessTimestamp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "gnw_alfred_last_success_timestamp_seconds",
+		Help: "Unix timestamp of the last successful post, 0 if none yet.",
+	})
+)
+
+// runDaemon crawls and posts to alfred on every tick of c.CrawlInterval
+// until it is asked to shut down via SIGINT/SIGTERM. A failed post is
+// retried with exponential backoff rather than abandoning the whole
+// cycle, so a transient monitoring outage doesn't cost a full interval.
+func runDaemon(c Config) error {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+
+	ticker := time.NewTicker(c.CrawlInterval)
+	defer ticker.Stop()
+
+	runCycle(c)
+
+	for {
+		select {
+		case <-ticker.C:
+			runCycle(c)
+		case sig := <-sigCh:
+			fmt.Printf("received %s, shutting down (see /metrics for cycle counts)\n", sig)
+			return nil
+		}
+	}
+}
+
+// runCycle performs a single crawl-and-post cycle, recording the outcome
+// in the alfred post metrics. Errors are logged but never propagated, so
+// a bad cycle doesn't take the daemon down; the next tick gets another
+// chance.
+func runCycle(c Config) {
+	d, err := crawl(buildNeighbourSources(c))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "crawl failed:", err)
+		alfredPostFailureTotal.Inc()
+		return
+	}
+
+	applyConfig(&d, c)
+
+	payload, err := buildAlfredPayload(d)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to build payload:", err)
+		alfredPostFailureTotal.Inc()
+		return
+	}
+
+	if c.Dry {
+		alfredPostSuccessTotal.Inc()
+		alfredLastSuccessTimestamp.SetToCurrentTime()
+		return
+	}
+
+	if err := postWithBackoff(payload); err != nil {
+		fmt.Fprintln(os.Stderr, "post to alfred failed after retries:", err)
+		alfredPostFailureTotal.Inc()
+		return
+	}
+
+	alfredPostSuccessTotal.Inc()
+	alfredLastSuccessTimestamp.SetToCurrentTime()
+}
+
+// postWithBackoff posts payload to alfred, retrying on error or non-2xx
+// responses with exponential backoff and full jitter between attempts.
+// It gives up after backoffMaxRetries attempts rather than blocking
+// until the next tick.
+func postWithBackoff(payload []byte) error {
+	var lastErr error
+
+	for attempt := 0; attempt < backoffMaxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoffDelay(attempt))
+		}
+
+		err := postToAlfred(payload)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+	}
+
+	return lastErr
+}
+
+// backoffDelay returns the delay before the given retry attempt
+// (1-indexed), using exponential backoff capped at backoffMaxDelay with
+// full jitter applied.
+func backoffDelay(attempt int) time.Duration {
+	delay := backoffBaseDelay << uint(attempt-1)
+	if delay > backoffMaxDelay {
+		delay = backoffMaxDelay
+	}
+	return time.Duration(rand.Int63n(int64(delay)))
+}
+
+// postToAlfred posts payload to the alfred monitoring endpoint and
+// returns an error if the request fails or the response status is not
+// 2xx.
+func postToAlfred(payload []byte) error {
+	resp, err := http.Post("https://monitoring.freifunk-franken.de/api/alfred", "application/json; charset=UTF-8", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("alfred returned status %s", resp.Status)
+	}
+
+	return nil
+}
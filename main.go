@@ -1,7 +1,6 @@
 package main
 
 import (
-	"bufio"
 	"bytes"
 	"encoding/xml"
 	"fmt"
@@ -64,19 +63,20 @@ type Data struct {
 	BatmanAdvGatewayMode string `xml:"batman_adv_gateway_mode"`
 	BatmanAdvGatewayList string `xml:"batman_adv_gateway_list"`
 	BabelNeighbours      struct {
-		Neighbours []BabelNeighbour `xml:"neighbour"`
+		Neighbours []Neighbour `xml:"neighbour"`
 	} `xml:"babel_neighbours"`
+	OLSR2Neighbours struct {
+		Neighbours []Neighbour `xml:"neighbour"`
+	} `xml:"olsr2_neighbours"`
+	Bmx7Neighbours struct {
+		Neighbours []Neighbour `xml:"neighbour"`
+	} `xml:"bmx7_neighbours"`
 	ClientCount int `xml:"client_count"`
 	Clients     struct {
 		Num []ClientNum
 	} `xml:"clients"`
 }
 
-type BabelNeighbour struct {
-	MacAddr           string `xml:",chardata"`
-	OutgoingInterface string `xml:"outgoing_interface"`
-}
-
 type Interface struct {
 	XMLName   xml.Name
 	Name      string `xml:"name"`
@@ -91,46 +91,7 @@ type ClientNum struct {
 	N       int `xml:",chardata"`
 }
 
-func getBabelNeighbours() []BabelNeighbour {
-	conn, err := net.Dial("tcp6", "[::1]:33123")
-	if err != nil {
-		return nil
-	}
-	defer conn.Close()
-
-	go fmt.Fprintln(conn, "dump")
-
-	scanner := bufio.NewScanner(conn)
-
-	var neighs []BabelNeighbour
-	// skip the startup "ok"
-	for scanner.Scan() {
-		if scanner.Text() == "ok" {
-			break
-		}
-	}
-	for scanner.Scan() {
-		fields := strings.Fields(scanner.Text())
-		if len(fields) == 1 && fields[0] == "ok" {
-			break
-		}
-		if len(fields) < 21 || fields[1] != "neighbour" {
-			continue
-		}
-		neighs = append(neighs, BabelNeighbour{
-			MacAddr:           fields[4],
-			OutgoingInterface: fields[6],
-		})
-
-	}
-	if scanner.Err() != nil {
-		return nil
-	}
-
-	return neighs
-}
-
-func crawl() (d Data, err error) {
+func crawl(sources []NeighbourSource) (d Data, err error) {
 	stat, err := procfs.NewStat()
 	if err != nil {
 		return
@@ -222,7 +183,25 @@ func crawl() (d Data, err error) {
 		})
 	}
 
-	d.BabelNeighbours.Neighbours = getBabelNeighbours()
+	// Routing-daemon neighbours are mesh peers, not the LAN clients
+	// ClientCount/Clients.Num track, and their identifiers (a babel or
+	// OLSR2 peer address) don't live in the same namespace as the
+	// kernel's MAC-keyed neighbour table above — there's no reliable
+	// way to dedupe the two against each other. Report them only via
+	// their own per-protocol neighbour lists.
+	for _, source := range sources {
+		neighs := source.GetNeighbours()
+
+		switch source.Name() {
+		case "babel":
+			d.BabelNeighbours.Neighbours = neighs
+		case "olsr2":
+			d.OLSR2Neighbours.Neighbours = neighs
+		case "bmx7":
+			d.Bmx7Neighbours.Neighbours = neighs
+		}
+	}
+
 	return d, err
 }
 
@@ -237,6 +216,39 @@ func parseUtsString(s [65]int8) string {
 	return buf.String()
 }
 
+// applyConfig copies the node's static, operator-supplied configuration
+// into the freshly crawled Data.
+func applyConfig(d *Data, c Config) {
+	d.SystemData.Hostname = c.Hostname
+	d.SystemData.Hood = c.Hood
+	d.SystemData.Hoodid = c.Hoodid
+	d.SystemData.Contact = c.Contact
+	d.SystemData.Distname = c.Distname
+	d.SystemData.Distversion = c.Distversion
+	d.SystemData.FirmwareVersion = "Generic"
+	d.SystemData.Geo.Lat = c.Lat
+	d.SystemData.Geo.Lng = c.Lng
+	d.SystemData.NodewatcherVersion = VERSION
+}
+
+// buildAlfredPayload wraps d's XML encoding in the JSON envelope alfred
+// expects.
+func buildAlfredPayload(d Data) ([]byte, error) {
+	if len(d.InterfaceData.Interfaces) == 0 {
+		return nil, fmt.Errorf("no interfaces to key the alfred payload by")
+	}
+
+	xpayload, err := xml.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `{%q: {%q: %q}}`, "64", d.InterfaceData.Interfaces[0].MacAddr, `<?xml version='1.0' standalone='yes'?>`+string(xpayload))
+
+	return buf.Bytes(), nil
+}
+
 func main() {
 	c, err := getConfig()
 	if err != nil {
@@ -244,20 +256,23 @@ func main() {
 		os.Exit(1)
 	}
 
-	d, err := crawl()
+	if c.MetricsAddr != "" {
+		startMetricsServer(c.MetricsAddr, c)
+	}
+
+	if c.Daemon {
+		if err := runDaemon(c); err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	d, err := crawl(buildNeighbourSources(c))
 	if err != nil {
 		panic(err)
 	}
 
-	d.SystemData.Hostname = c.Hostname
-	d.SystemData.Hood = c.Hood
-	d.SystemData.Contact = c.Contact
-	d.SystemData.Distname = c.Distname
-	d.SystemData.Distversion = c.Distversion
-	d.SystemData.FirmwareVersion = "Generic"
-	d.SystemData.Geo.Lat = c.Lat
-	d.SystemData.Geo.Lng = c.Lng
-	d.SystemData.NodewatcherVersion = VERSION
+	applyConfig(&d, c)
 
 	if c.Debug {
 		fmt.Println("XML Output:")
@@ -269,31 +284,20 @@ func main() {
 		fmt.Println()
 	}
 
-	xpayload, err := xml.Marshal(d)
+	payload, err := buildAlfredPayload(d)
 	if err != nil {
 		panic(err)
 	}
 
-	if c.Debug {
-		fmt.Println()
-		fmt.Println("XML Payload:")
-		fmt.Println()
-		fmt.Println(string(xpayload))
-	}
-
-	var buf bytes.Buffer
-
-	fmt.Fprintf(&buf, `{%q: {%q: %q}}`, "64", d.InterfaceData.Interfaces[0].MacAddr, `<?xml version='1.0' standalone='yes'?>`+string(xpayload))
-
 	if c.Debug {
 		fmt.Println()
 		fmt.Println("JSON Output:")
 		fmt.Println()
-		fmt.Println(buf.String())
+		fmt.Println(string(payload))
 	}
 
 	if !c.Dry {
-		resp, err := http.Post("https://monitoring.freifunk-franken.de/api/alfred", "application/json; charset=UTF-8", &buf)
+		resp, err := http.Post("https://monitoring.freifunk-franken.de/api/alfred", "application/json; charset=UTF-8", bytes.NewReader(payload))
 		if err != nil {
 			panic(err)
 		}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// startMetricsServer launches the Prometheus "/metrics" endpoint on
+// addr in the background. It returns immediately; ListenAndServe errors
+// are logged rather than propagated, since a dead metrics endpoint
+// shouldn't take the rest of gnw down with it.
+func startMetricsServer(addr string, c Config) {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(&nodeCollector{config: c})
+	registry.MustRegister(alfredPostSuccessTotal, alfredPostFailureTotal, alfredLastSuccessTimestamp)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			fmt.Println("metrics server stopped:", err)
+		}
+	}()
+}
+
+var (
+	memoryTotalDesc = prometheus.NewDesc(
+		"gnw_memory_total_kilobytes", "Total memory in kilobytes.", nodeLabelNames, nil)
+	memoryFreeDesc = prometheus.NewDesc(
+		"gnw_memory_free_kilobytes", "Free memory in kilobytes.", nodeLabelNames, nil)
+	memoryBufferingDesc = prometheus.NewDesc(
+		"gnw_memory_buffering_kilobytes", "Memory used for buffering in kilobytes.", nodeLabelNames, nil)
+	memoryCachingDesc = prometheus.NewDesc(
+		"gnw_memory_caching_kilobytes", "Memory used for caching in kilobytes.", nodeLabelNames, nil)
+	loadavgDesc = prometheus.NewDesc(
+		"gnw_loadavg", "15 minute load average.", nodeLabelNames, nil)
+	uptimeDesc = prometheus.NewDesc(
+		"gnw_uptime_seconds", "System uptime in seconds.", nodeLabelNames, nil)
+	babelNeighboursDesc = prometheus.NewDesc(
+		"gnw_babel_neighbours", "Number of babel neighbours.", nodeLabelNames, nil)
+	olsr2NeighboursDesc = prometheus.NewDesc(
+		"gnw_olsr2_neighbours", "Number of OLSR2 neighbours.", nodeLabelNames, nil)
+	bmx7NeighboursDesc = prometheus.NewDesc(
+		"gnw_bmx7_neighbours", "Number of bmx7 neighbours.", nodeLabelNames, nil)
+	clientCountDesc = prometheus.NewDesc(
+		"gnw_client_count", "Number of reachable neighbours per interface.", ifaceLabelNames, nil)
+	trafficRxDesc = prometheus.NewDesc(
+		"gnw_interface_traffic_rx_bytes_total", "Received bytes per interface.", macIfaceLabelNames, nil)
+	trafficTxDesc = prometheus.NewDesc(
+		"gnw_interface_traffic_tx_bytes_total", "Transmitted bytes per interface.", macIfaceLabelNames, nil)
+)
+
+// nodeLabelNames, ifaceLabelNames and macIfaceLabelNames are the label
+// sets shared by the node-wide and per-interface descriptors above.
+var (
+	nodeLabelNames     = []string{"hostname", "hood"}
+	ifaceLabelNames    = []string{"hostname", "hood", "interface"}
+	macIfaceLabelNames = []string{"hostname", "hood", "interface", "mac"}
+)
+
+// nodeCollector implements prometheus.Collector by crawling the node
+// fresh on every scrape (pull model), so the exported values never go
+// stale between requests.
+type nodeCollector struct {
+	config Config
+}
+
+func (nc *nodeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- memoryTotalDesc
+	ch <- memoryFreeDesc
+	ch <- memoryBufferingDesc
+	ch <- memoryCachingDesc
+	ch <- loadavgDesc
+	ch <- uptimeDesc
+	ch <- babelNeighboursDesc
+	ch <- olsr2NeighboursDesc
+	ch <- bmx7NeighboursDesc
+	ch <- clientCountDesc
+	ch <- trafficRxDesc
+	ch <- trafficTxDesc
+}
+
+func (nc *nodeCollector) Collect(ch chan<- prometheus.Metric) {
+	d, err := crawl(buildNeighbourSources(nc.config))
+	if err != nil {
+		fmt.Println("metrics crawl failed:", err)
+		return
+	}
+
+	nodeLabels := []string{nc.config.Hostname, nc.config.Hood}
+
+	ch <- prometheus.MustNewConstMetric(memoryTotalDesc, prometheus.GaugeValue, float64(d.SystemData.MemoryTotal), nodeLabels...)
+	ch <- prometheus.MustNewConstMetric(memoryFreeDesc, prometheus.GaugeValue, float64(d.SystemData.MemoryFree), nodeLabels...)
+	ch <- prometheus.MustNewConstMetric(memoryBufferingDesc, prometheus.GaugeValue, float64(d.SystemData.MemoryBuffering), nodeLabels...)
+	ch <- prometheus.MustNewConstMetric(memoryCachingDesc, prometheus.GaugeValue, float64(d.SystemData.MemoryCaching), nodeLabels...)
+	ch <- prometheus.MustNewConstMetric(loadavgDesc, prometheus.GaugeValue, d.SystemData.Loadavg, nodeLabels...)
+	ch <- prometheus.MustNewConstMetric(uptimeDesc, prometheus.GaugeValue, float64(d.SystemData.Uptime), nodeLabels...)
+	ch <- prometheus.MustNewConstMetric(babelNeighboursDesc, prometheus.GaugeValue, float64(len(d.BabelNeighbours.Neighbours)), nodeLabels...)
+	ch <- prometheus.MustNewConstMetric(olsr2NeighboursDesc, prometheus.GaugeValue, float64(len(d.OLSR2Neighbours.Neighbours)), nodeLabels...)
+	ch <- prometheus.MustNewConstMetric(bmx7NeighboursDesc, prometheus.GaugeValue, float64(len(d.Bmx7Neighbours.Neighbours)), nodeLabels...)
+
+	for _, num := range d.Clients.Num {
+		ch <- prometheus.MustNewConstMetric(clientCountDesc, prometheus.GaugeValue, float64(num.N),
+			nc.config.Hostname, nc.config.Hood, num.XMLName.Local)
+	}
+
+	for _, iface := range d.InterfaceData.Interfaces {
+		ch <- prometheus.MustNewConstMetric(trafficRxDesc, prometheus.CounterValue, float64(iface.TrafficRx),
+			nc.config.Hostname, nc.config.Hood, iface.Name, iface.MacAddr)
+		ch <- prometheus.MustNewConstMetric(trafficTxDesc, prometheus.CounterValue, float64(iface.TrafficTx),
+			nc.config.Hostname, nc.config.Hood, iface.Name, iface.MacAddr)
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// neighbourSourceTimeout bounds how long a NeighbourSource will wait for
+// a routing daemon to answer, so a hung daemon can't block a crawl (or
+// leak a goroutine/socket on every Prometheus scrape).
+const neighbourSourceTimeout = 5 * time.Second
+
+// dialNeighbourSource dials network with a connect timeout and arms an
+// overall deadline for the life of the connection.
+func dialNeighbourSource(network, endpoint string) (net.Conn, error) {
+	conn, err := net.DialTimeout(network, endpoint, neighbourSourceTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.SetDeadline(time.Now().Add(neighbourSourceTimeout)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// Neighbour is a routing-daemon neighbour as reported by any
+// NeighbourSource. It is shared across the babel_neighbours,
+// olsr2_neighbours and bmx7_neighbours XML elements, which all carry the
+// same mac_addr/outgoing_interface pair.
+type Neighbour struct {
+	MacAddr           string `xml:",chardata"`
+	OutgoingInterface string `xml:"outgoing_interface"`
+}
+
+// NeighbourSource discovers routing-daemon neighbours for a single
+// routing protocol (babel, OLSR2, bmx7, ...).
+type NeighbourSource interface {
+	// Name identifies the source. crawl() uses it to choose the XML
+	// neighbour list ("babel", "olsr2" or "bmx7"); neighbours from a
+	// source with any other name are not reported.
+	Name() string
+	// GetNeighbours returns the neighbours currently known to the
+	// routing daemon. A nil/empty result means none were found or the
+	// daemon couldn't be reached; it is not treated as a fatal error.
+	GetNeighbours() []Neighbour
+}
+
+// buildNeighbourSources turns the enabled sources in c into a slice of
+// NeighbourSource ready to be queried by crawl().
+func buildNeighbourSources(c Config) []NeighbourSource {
+	var sources []NeighbourSource
+
+	if c.BabelEnabled {
+		sources = append(sources, babelSource{endpoint: c.BabelEndpoint})
+	}
+	if c.OLSR2Enabled {
+		sources = append(sources, olsr2Source{endpoint: c.OLSR2Endpoint})
+	}
+	if c.Bmx7Enabled {
+		sources = append(sources, bmx7Source{endpoint: c.Bmx7Endpoint})
+	}
+
+	return sources
+}
+
+// babelSource discovers neighbours via babeld's text-protocol control
+// socket ("dump" command).
+type babelSource struct {
+	endpoint string
+}
+
+func (s babelSource) Name() string { return "babel" }
+
+func (s babelSource) GetNeighbours() []Neighbour {
+	conn, err := dialNeighbourSource("tcp6", s.endpoint)
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+
+	go fmt.Fprintln(conn, "dump")
+
+	scanner := bufio.NewScanner(conn)
+
+	var neighs []Neighbour
+	// skip the startup "ok"
+	for scanner.Scan() {
+		if scanner.Text() == "ok" {
+			break
+		}
+	}
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 1 && fields[0] == "ok" {
+			break
+		}
+		if len(fields) < 21 || fields[1] != "neighbour" {
+			continue
+		}
+		neighs = append(neighs, Neighbour{
+			MacAddr:           fields[4],
+			OutgoingInterface: fields[6],
+		})
+	}
+	if scanner.Err() != nil {
+		return nil
+	}
+
+	return neighs
+}
+
+// olsr2Source discovers neighbours via olsrd's jsoninfo plugin, which
+// serves a JSON document describing the link database over a plain TCP
+// connection: writing the request path (e.g. "/links") followed by a
+// newline returns that endpoint's JSON body directly, with no HTTP
+// framing.
+//
+// jsoninfo's "/links" response carries "localIP"/"remoteIP"/"ifName"
+// per link, not a MAC address — OLSR routes at the IP layer, same as
+// Babel. We carry remoteIP in the Neighbour's MacAddr field for the
+// same reason the existing babelSource does: the XML schema's
+// mac_addr/neighbour pairing predates multi-protocol support and is
+// really "peer address, outgoing interface".
+type olsr2Source struct {
+	endpoint string
+}
+
+func (s olsr2Source) Name() string { return "olsr2" }
+
+type olsr2LinksResponse struct {
+	Links []struct {
+		LocalIP  string `json:"localIP"`
+		RemoteIP string `json:"remoteIP"`
+		IfName   string `json:"ifName"`
+	} `json:"links"`
+}
+
+func (s olsr2Source) GetNeighbours() []Neighbour {
+	conn, err := dialNeighbourSource("tcp", s.endpoint)
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+
+	fmt.Fprint(conn, "/links\n")
+
+	var resp olsr2LinksResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil
+	}
+
+	var neighs []Neighbour
+	for _, l := range resp.Links {
+		if l.RemoteIP == "" {
+			continue
+		}
+		neighs = append(neighs, Neighbour{
+			MacAddr:           l.RemoteIP,
+			OutgoingInterface: l.IfName,
+		})
+	}
+
+	return neighs
+}
+
+// bmx7Source discovers neighbours via bmx7's JSON status query, sent as
+// a single line over a plain TCP connection to bmx7's control socket,
+// the same framing bmx7's own "bmx7 -c json status" CLI uses.
+//
+// Like olsr2Source, this is disabled by default and its exact field
+// names are a best-effort mapping rather than one checked against a
+// live bmx7 instance: bmx7 reports a node's primaryIp and the local
+// device a link was learned on (viaDev), not a MAC address, so
+// primaryIp fills the Neighbour's MacAddr field the same way
+// olsr2Source's remoteIP does.
+type bmx7Source struct {
+	endpoint string
+}
+
+func (s bmx7Source) Name() string { return "bmx7" }
+
+type bmx7StatusResponse struct {
+	Status []struct {
+		PrimaryIP string `json:"primaryIp"`
+		ViaDev    string `json:"viaDev"`
+	} `json:"status"`
+}
+
+func (s bmx7Source) GetNeighbours() []Neighbour {
+	conn, err := dialNeighbourSource("tcp", s.endpoint)
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+
+	fmt.Fprint(conn, "status\n")
+
+	var resp bmx7StatusResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil
+	}
+
+	var neighs []Neighbour
+	for _, entry := range resp.Status {
+		if entry.PrimaryIP == "" {
+			continue
+		}
+		neighs = append(neighs, Neighbour{
+			MacAddr:           entry.PrimaryIP,
+			OutgoingInterface: entry.ViaDev,
+		})
+	}
+
+	return neighs
+}